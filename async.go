@@ -0,0 +1,138 @@
+package logg
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWriterClosed is returned when writing to an AsyncWriter after it's closed.
+var ErrWriterClosed = errors.New("logg: write to closed AsyncWriter")
+
+// An AsyncWriter decouples emitting an event from the I/O of writing it. Each
+// write is queued on a buffered channel and written to the underlying sink on
+// a background goroutine, so a slow sink doesn't block the caller. When the
+// buffer is full, the write is dropped rather than blocking; see Dropped.
+//
+// Use an AsyncWriter as a sink for Configure or New. Call Close when done, such
+// as before the application exits, to write everything left in the buffer.
+type AsyncWriter struct {
+	// dropped is first so that it's 64-bit aligned for atomic operations on
+	// 32-bit platforms.
+	dropped uint64
+
+	next    io.Writer
+	entries chan asyncEntry
+	done    chan struct{}
+
+	mtx    sync.RWMutex
+	closed bool
+
+	// errMtx is separate from mtx so the background goroutine never waits on
+	// a writer, which may be waiting on the goroutine to free up the buffer.
+	errMtx sync.Mutex
+	err    error
+}
+
+// asyncEntry is either some data to write or a request to flush. When flushed
+// is non-nil, it's closed after all previous entries are written.
+type asyncEntry struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// NewAsyncWriter initializes an AsyncWriter, which writes to next and queues up
+// to bufferSize writes. A bufferSize less than 1 is treated as 1, since there
+// must be room to queue a write. It starts a background goroutine, which exits
+// after calling Close.
+func NewAsyncWriter(next io.Writer, bufferSize int) *AsyncWriter {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	w := &AsyncWriter{
+		next:    next,
+		entries: make(chan asyncEntry, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go w.drain()
+	return w
+}
+
+func (w *AsyncWriter) drain() {
+	defer close(w.done)
+	for entry := range w.entries {
+		if entry.flushed != nil {
+			close(entry.flushed)
+			continue
+		}
+		if _, err := w.next.Write(entry.data); err != nil {
+			w.errMtx.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.errMtx.Unlock()
+		}
+	}
+}
+
+// Write queues a copy of p to be written in the background. If the buffer is
+// full, then p is dropped.
+func (w *AsyncWriter) Write(p []byte) (n int, err error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	if w.closed {
+		atomic.AddUint64(&w.dropped, 1)
+		err = ErrWriterClosed
+		return
+	}
+
+	// The caller may reuse p after returning, so it must be copied.
+	entry := asyncEntry{data: append([]byte(nil), p...)}
+	select {
+	case w.entries <- entry:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	n = len(p)
+	return
+}
+
+// Flush blocks until all writes queued so far are written to the underlying
+// sink. It's a no-op after Close.
+func (w *AsyncWriter) Flush() error {
+	w.mtx.RLock()
+	if w.closed {
+		w.mtx.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	w.entries <- asyncEntry{flushed: flushed}
+	w.mtx.RUnlock()
+
+	<-flushed
+	return nil
+}
+
+// Dropped is the number of writes dropped because the buffer was full, or
+// because the writer was closed.
+func (w *AsyncWriter) Dropped() uint64 { return atomic.LoadUint64(&w.dropped) }
+
+// Close stops accepting writes, writes everything left in the buffer and stops
+// the background goroutine. The output is the first error from writing to the
+// underlying sink, if any. It's safe to call more than once.
+func (w *AsyncWriter) Close() error {
+	w.mtx.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mtx.Unlock()
+
+	<-w.done
+
+	w.errMtx.Lock()
+	defer w.errMtx.Unlock()
+	return w.err
+}
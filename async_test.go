@@ -0,0 +1,119 @@
+package logg_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/rafaelespinoza/logg"
+)
+
+func TestAsyncWriter(t *testing.T) {
+	t.Run("writes in background", func(t *testing.T) {
+		sink := newDataSink()
+		w := logg.NewAsyncWriter(sink, 8)
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, w)
+
+		logger.Infof("hello")
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		testLogg(t, sink.Raw(), nil, "hello", false, map[string]interface{}{"sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+
+		logger.Infof("goodbye")
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		testLogg(t, sink.Raw(), nil, "goodbye", false, map[string]interface{}{"sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+
+		if err := w.Close(); err != nil {
+			t.Errorf("expected closing again to be ok; got %v", err)
+		}
+		if got := w.Dropped(); got != 0 {
+			t.Errorf("wrong dropped count; got %d, expected %d", got, 0)
+		}
+	})
+
+	t.Run("drops when buffer is full", func(t *testing.T) {
+		sink := &blockingSink{unblock: make(chan struct{}), started: make(chan struct{})}
+		w := logg.NewAsyncWriter(sink, 1)
+
+		// The first write is taken off the buffer and blocks in the sink, the
+		// second fills up the buffer and the rest are dropped.
+		_, _ = w.Write([]byte("alfa"))
+		<-sink.started
+		for _, in := range []string{"bravo", "charlie", "delta"} {
+			if _, err := w.Write([]byte(in)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := w.Dropped(); got != 2 {
+			t.Errorf("wrong dropped count; got %d, expected %d", got, 2)
+		}
+
+		close(sink.unblock)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := sink.buf.String(); got != "alfabravo" {
+			t.Errorf("wrong output; got %q, expected %q", got, "alfabravo")
+		}
+
+		// writing after closing is an error.
+		if _, err := w.Write([]byte("echo")); !errors.Is(err, logg.ErrWriterClosed) {
+			t.Errorf("wrong error; got %v, expected %v", err, logg.ErrWriterClosed)
+		}
+		if got := w.Dropped(); got != 3 {
+			t.Errorf("wrong dropped count; got %d, expected %d", got, 3)
+		}
+	})
+
+	t.Run("buffer size less than 1", func(t *testing.T) {
+		for _, size := range []int{0, -1} {
+			sink := newDataSink()
+			w := logg.NewAsyncWriter(sink, size)
+			logg.New(nil, w).Infof("hello")
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			testLogg(t, sink.Raw(), nil, "hello", false, map[string]interface{}{})
+			if got := w.Dropped(); got != 0 {
+				t.Errorf("size %d; wrong dropped count; got %d, expected %d", size, got, 0)
+			}
+		}
+	})
+
+	t.Run("reports write errors on close", func(t *testing.T) {
+		w := logg.NewAsyncWriter(errSink{}, 1)
+		_, _ = w.Write([]byte("alfa"))
+		if err := w.Close(); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+// blockingSink blocks each write until unblock is closed. The started channel
+// is closed when the first write begins.
+type blockingSink struct {
+	buf     bytes.Buffer
+	once    sync.Once
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (s *blockingSink) Write(in []byte) (int, error) {
+	s.once.Do(func() { close(s.started) })
+	<-s.unblock
+	return s.buf.Write(in)
+}
+
+type errSink struct{}
+
+func (errSink) Write(in []byte) (int, error) { return 0, errors.New("test") }
@@ -0,0 +1,112 @@
+package logg
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/rs/zerolog"
+)
+
+// flagsFieldName is the logging entry key for feature flags on a context.
+const flagsFieldName = "flags"
+
+type (
+	dataCtxKey  struct{}
+	flagsCtxKey struct{}
+)
+
+// WithDataContext returns a copy of ctx, which carries data fields for loggers
+// created with FromContext further down the call tree. The fields are merged
+// with any fields already on ctx; the input fields override conflicting keys.
+// Neither the input fields nor the fields already on ctx are modified.
+func WithDataContext(ctx context.Context, fields map[string]interface{}) context.Context {
+	tmp := shallowDupe(dataFromCtx(ctx))
+	return context.WithValue(ctx, dataCtxKey{}, mergeFields(tmp, fields))
+}
+
+// FromContext initializes a logger Emitter like New, using any data fields
+// accumulated on ctx with WithDataContext. See NewContext to also use the
+// tracing ID on ctx.
+func FromContext(ctx context.Context, sinks ...io.Writer) Emitter {
+	return New(dataFromCtx(ctx), sinks...)
+}
+
+func dataFromCtx(ctx context.Context) map[string]interface{} {
+	fields, _ := ctx.Value(dataCtxKey{}).(map[string]interface{})
+	return fields
+}
+
+// contextFields merges any data fields on ctx, from WithDataContext, with
+// fields. The input fields override conflicting keys. If there are no data
+// fields on ctx, then the output is fields as-is.
+func contextFields(ctx context.Context, fields map[string]interface{}) map[string]interface{} {
+	ctxFields := dataFromCtx(ctx)
+	if len(ctxFields) == 0 {
+		return fields
+	}
+	return ResolveFields(ctx, fields)
+}
+
+// ResolveFields combines any data fields on ctx, from WithDataContext, with the
+// explicit fields. The explicit fields override conflicting keys. The output is
+// a new map; neither the input fields nor the fields on ctx are modified.
+func ResolveFields(ctx context.Context, explicit map[string]interface{}) map[string]interface{} {
+	return mergeFields(shallowDupe(dataFromCtx(ctx)), explicit)
+}
+
+// WithFlags returns a copy of ctx, which carries the active feature flags. The
+// flags are written to the flags field of events emitted with the Emitter
+// methods that take a context. They are merged with any flags already on ctx;
+// the input flags override conflicting keys.
+func WithFlags(ctx context.Context, flags map[string]bool) context.Context {
+	prev := flagsFromCtx(ctx)
+	next := make(map[string]bool, len(prev)+len(flags))
+	for key, val := range prev {
+		next[key] = val
+	}
+	for key, val := range flags {
+		next[key] = val
+	}
+	return context.WithValue(ctx, flagsCtxKey{}, next)
+}
+
+func flagsFromCtx(ctx context.Context) map[string]bool {
+	flags, _ := ctx.Value(flagsCtxKey{}).(map[string]bool)
+	return flags
+}
+
+// addContextFlags writes any feature flags on ctx to evt, in key order. It's a
+// no-op when there aren't any flags or when the event is dropped.
+func addContextFlags(ctx context.Context, evt *zerolog.Event) *zerolog.Event {
+	flags := flagsFromCtx(ctx)
+	if len(flags) == 0 || evt == nil {
+		return evt
+	}
+
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dict := zerolog.Dict()
+	for _, key := range keys {
+		dict = dict.Bool(key, flags[key])
+	}
+	return evt.Dict(flagsFieldName, dict)
+}
+
+// addContext writes details from ctx to evt, for the Emitter methods that take
+// a context. The tracing ID on ctx is only added when the Emitter does not
+// already have one from WithID, which takes precedence.
+func addContext(ctx context.Context, evt *zerolog.Event, traced bool) *zerolog.Event {
+	evt = addContextFlags(ctx, evt)
+	if traced || evt == nil {
+		return evt
+	}
+	if id, ok := lookupID(ctx); ok {
+		evt = evt.Str(traceIDFieldName, outputID(id))
+	}
+	return evt
+}
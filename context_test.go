@@ -0,0 +1,280 @@
+package logg_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/logg"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Run("no data on context", func(t *testing.T) {
+		sink := newDataSink()
+		logg.FromContext(context.Background(), sink).Infof("a")
+		testLogg(t, sink.Raw(), nil, "a", false, map[string]interface{}{})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("inherits data from derived contexts", func(t *testing.T) {
+		sink := newDataSink()
+
+		parent := logg.WithDataContext(context.Background(), map[string]interface{}{"foo": "alfa", "sierra": "nevada"})
+		child := logg.WithDataContext(parent, map[string]interface{}{"foo": "bravo", "zulu": true})
+
+		logg.FromContext(child, sink).Infof("b")
+		testLogg(t, sink.Raw(), nil, "b", false, map[string]interface{}{"foo": "bravo", "sierra": "nevada", "zulu": true})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+
+		// check that the parent context's data hasn't changed unexpectedly.
+		logg.FromContext(parent, sink).Infof("c")
+		testLogg(t, sink.Raw(), nil, "c", false, map[string]interface{}{"foo": "alfa", "sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+}
+
+func TestContextMethods(t *testing.T) {
+	ctx := logg.WithDataContext(context.Background(), map[string]interface{}{"foo": "alfa", "zulu": true})
+
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"foo": "bravo", "sierra": "nevada"}, sink)
+
+	// The Emitter's own fields take precedence.
+	logger.InfofContext(ctx, "a")
+	testLogg(t, sink.Raw(), nil, "a", false, map[string]interface{}{"foo": "bravo", "sierra": "nevada", "zulu": true})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	logger.ErrorfContext(ctx, errors.New("oops"), "b")
+	testLogg(t, sink.Raw(), errors.New("oops"), "b", false, map[string]interface{}{"foo": "bravo", "sierra": "nevada", "zulu": true})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	event := logger.WithData(map[string]interface{}{"bravo": true})
+	event.InfofContext(ctx, "c")
+	testLogg(t, sink.Raw(), nil, "c", false, map[string]interface{}{"bravo": true, "foo": "bravo", "sierra": "nevada", "zulu": true})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	event.ErrorfContext(ctx, errors.New("oops"), "d")
+	testLogg(t, sink.Raw(), errors.New("oops"), "d", false, map[string]interface{}{"bravo": true, "foo": "bravo", "sierra": "nevada", "zulu": true})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the Emitter's fields haven't changed unexpectedly.
+	logger.Infof("e")
+	testLogg(t, sink.Raw(), nil, "e", false, map[string]interface{}{"foo": "bravo", "sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestResolveFields(t *testing.T) {
+	ctx := logg.WithDataContext(context.Background(), map[string]interface{}{"foo": "alfa", "zulu": true})
+	explicit := map[string]interface{}{"foo": "bravo", "sierra": "nevada"}
+
+	got := logg.ResolveFields(ctx, explicit)
+	exp := map[string]interface{}{"foo": "bravo", "sierra": "nevada", "zulu": true}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("wrong output; got %v, expected %v", got, exp)
+	}
+
+	// check that the explicit fields haven't changed unexpectedly.
+	if _, ok := explicit["zulu"]; ok {
+		t.Errorf("explicit fields were modified; got %v", explicit)
+	}
+
+	got = logg.ResolveFields(context.Background(), nil)
+	if len(got) != 0 {
+		t.Errorf("expected empty output; got %v", got)
+	}
+}
+
+func TestWithFlags(t *testing.T) {
+	const flagsKey = "flags"
+
+	testFlags := func(t *testing.T, in []byte, exp map[string]interface{}) {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		val, ok := parsedRoot[flagsKey]
+		if exp == nil {
+			if ok {
+				t.Errorf("unexpected value at %q; got %v", flagsKey, val)
+			}
+		} else if !ok {
+			t.Errorf("expected to have key %q", flagsKey)
+		} else if !reflect.DeepEqual(val, exp) {
+			t.Errorf("wrong value at %q; got %v, expected %v", flagsKey, val, exp)
+		}
+		if t.Failed() {
+			t.Logf("%s", in)
+		}
+	}
+
+	parent := logg.WithFlags(context.Background(), map[string]bool{"alfa": true, "bravo": true})
+	child := logg.WithFlags(parent, map[string]bool{"bravo": false, "charlie": true})
+
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	logger.InfofContext(child, "a")
+	testFlags(t, sink.Raw(), map[string]interface{}{"alfa": true, "bravo": false, "charlie": true})
+
+	logger.WithData(map[string]interface{}{"zulu": true}).ErrorfContext(parent, errors.New("oops"), "b")
+	testFlags(t, sink.Raw(), map[string]interface{}{"alfa": true, "bravo": true})
+
+	// only included when present.
+	logger.InfofContext(context.Background(), "c")
+	testFlags(t, sink.Raw(), nil)
+
+	logger.Infof("d")
+	testFlags(t, sink.Raw(), nil)
+}
+
+func TestContextMethodsTraceID(t *testing.T) {
+	const traceIDKey = "x_trace_id"
+
+	parseTraceID := func(t *testing.T, in []byte) (id string) {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		if val, ok := parsedRoot[traceIDKey]; ok {
+			id = val.(string)
+		}
+		if n := strings.Count(string(in), traceIDKey); n > 1 {
+			t.Errorf("wrong count of %q values; got %d, expected <= %d", traceIDKey, n, 1)
+		}
+		return
+	}
+
+	t.Run("emitter-set", func(t *testing.T) {
+		emitterCtx := logg.CtxWithID(context.Background())
+		otherCtx := logg.CtxWithID(context.Background())
+		sink := newDataSink()
+		logger := logg.New(nil, sink).WithID(emitterCtx)
+
+		logger.Infof("a")
+		exp := parseTraceID(t, sink.Raw())
+
+		logger.InfofContext(otherCtx, "b")
+		if got := parseTraceID(t, sink.Raw()); got != exp {
+			t.Errorf("wrong id; got %q, expected %q", got, exp)
+		}
+
+		logger.WithData(map[string]interface{}{"bravo": true}).ErrorfContext(otherCtx, errors.New("oops"), "c")
+		if got := parseTraceID(t, sink.Raw()); got != exp {
+			t.Errorf("wrong id; got %q, expected %q", got, exp)
+		}
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("context-only", func(t *testing.T) {
+		ctx := logg.CtxWithID(context.Background())
+		sink := newDataSink()
+		logger := logg.New(nil, sink)
+
+		// Establish the expected ID from the context.
+		logg.New(nil, sink).WithID(ctx).Infof("a")
+		exp := parseTraceID(t, sink.Raw())
+
+		logger.InfofContext(ctx, "b")
+		if got := parseTraceID(t, sink.Raw()); got != exp {
+			t.Errorf("wrong id; got %q, expected %q", got, exp)
+		}
+
+		logger.WithData(map[string]interface{}{"bravo": true}).ErrorfContext(ctx, errors.New("oops"), "c")
+		if got := parseTraceID(t, sink.Raw()); got != exp {
+			t.Errorf("wrong id; got %q, expected %q", got, exp)
+		}
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("neither", func(t *testing.T) {
+		sink := newDataSink()
+		logger := logg.New(nil, sink)
+
+		logger.InfofContext(context.Background(), "a")
+		if got := parseTraceID(t, sink.Raw()); got != "" {
+			t.Errorf("unexpected id %q", got)
+		}
+
+		logger.WithData(map[string]interface{}{"bravo": true}).ErrorfContext(context.Background(), errors.New("oops"), "b")
+		if got := parseTraceID(t, sink.Raw()); got != "" {
+			t.Errorf("unexpected id %q", got)
+		}
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+}
+
+func TestIDNormalization(t *testing.T) {
+	type altKey struct{}
+
+	logg.SetIDFallback(func(ctx context.Context) (string, bool) {
+		id, ok := ctx.Value(altKey{}).(string)
+		return id, ok
+	})
+	defer logg.SetIDFallback(nil)
+
+	parseTraceID := func(t *testing.T, in []byte) string {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		id, _ := parsedRoot["x_trace_id"].(string)
+		return id
+	}
+
+	ctx := context.WithValue(context.Background(), altKey{}, "ABC-Def-123")
+	sink := newDataSink()
+
+	logg.New(nil, sink).InfofContext(ctx, "off")
+	if got := parseTraceID(t, sink.Raw()); got != "ABC-Def-123" {
+		t.Errorf("wrong id; got %q, expected %q", got, "ABC-Def-123")
+	}
+
+	logg.SetIDNormalization(true)
+	defer logg.SetIDNormalization(false)
+
+	emits := []func(){
+		func() { logg.New(nil, sink).InfofContext(ctx, "context") },
+		func() { logg.New(nil, sink).WithID(ctx).Infof("with id") },
+		func() {
+			logg.New(nil, sink).WithData(map[string]interface{}{"bravo": true}).WithID(ctx).Infof("event with id")
+		},
+		func() { logg.NewContext(ctx, nil, sink).Infof("new context") },
+	}
+	for i, emit := range emits {
+		emit()
+		if got := parseTraceID(t, sink.Raw()); got != "abcdef123" {
+			t.Errorf("item[%d]; wrong id; got %q, expected %q", i, got, "abcdef123")
+		}
+	}
+}
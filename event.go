@@ -2,21 +2,64 @@ package logg
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
 type event struct {
-	logger *zerolog.Logger
-	fields map[string]interface{}
+	logger  *zerolog.Logger
+	fields  map[string]interface{}
+	dataKey string
+	sinks   []io.Writer
+	groups  []string
+	traced  bool
+	err     error
 }
 
 func (e *event) Infof(msg string, args ...interface{}) {
-	newZerologInfoEvent(e.logger, e.fields).Msgf(msg, args...)
+	e.InfofContext(context.Background(), msg, args...)
+}
+
+func (e *event) InfofContext(ctx context.Context, msg string, args ...interface{}) {
+	evt := newZerologInfoEvent(ctx, e.logger, e.dataKey, e.fields).AnErr(zerolog.ErrorFieldName, e.err)
+	addContext(ctx, evt, e.traced).Msgf(msg, args...)
 }
 
 func (e *event) Errorf(err error, msg string, args ...interface{}) {
-	newZerologErrorEvent(e.logger, err, e.fields).Msgf(msg, args...)
+	e.ErrorfContext(context.Background(), err, msg, args...)
+}
+
+func (e *event) ErrorfContext(ctx context.Context, err error, msg string, args ...interface{}) {
+	if err == nil {
+		err = e.err
+	}
+	evt := newZerologErrorEvent(ctx, e.logger, err, e.dataKey, e.fields)
+	addContext(ctx, evt, e.traced).Msgf(msg, args...)
+}
+
+func (e *event) Err(err error) {
+	if err == nil {
+		err = e.err
+	}
+	e.Errorf(err, "%s", errMsg(err))
+}
+
+func (e *event) ErrorBatch(msg string, errs []BatchError) {
+	newZerologBatchEvent(e.logger, e.dataKey, e.fields, errs).Msg(msg)
+}
+
+func (e *event) Fatalf(err error, msg string, args ...interface{}) {
+	if err == nil {
+		err = e.err
+	}
+	fatal(newZerologFatalEvent(e.logger, err, e.dataKey, e.fields), e.sinks, msg, args...)
+}
+
+func (e *event) Msg(code string, args ...interface{}) {
+	evt := newZerologMsgEvent(e.logger, code, e.dataKey, e.fields).AnErr(zerolog.ErrorFieldName, e.err)
+	evt.Msgf(lookupMessage(code, len(args)), args...)
 }
 
 // WithID sets a tracing ID on the logging entry. If the event is constructed
@@ -38,9 +81,21 @@ func (e *event) WithID(ctx context.Context) Emitter {
 	// all the fields.
 	lgr := newZerologCtxWithID(ctx, e.logger).Logger()
 	e.logger = &lgr
+	e.traced = true
 	return e
 }
 
+// WithOpID sets an operation ID on the logging entry. Like WithID, calling this
+// on an event from a logger, which already has an operation ID, adds another
+// operation ID key-value pair to the logging entry. The receiver is unchanged.
+func (e *event) WithOpID(id string) Emitter {
+	lgr := e.logger.With().Str(opIDFieldName, id).Logger()
+
+	out := e.derive()
+	out.logger = &lgr
+	return out
+}
+
 const eventDebugWithDataMsg = "called WithData on an event; prefer calling WithData on a logger type"
 
 func (e *event) WithData(fields map[string]interface{}) Emitter {
@@ -48,13 +103,83 @@ func (e *event) WithData(fields map[string]interface{}) Emitter {
 	// in order to accept and merge fields into e.fields, preferring the new
 	// data in fields over any potentially conflicting keys in e.fields, but
 	// also try not to change to the original e.fields.
-	rootLogger().Debug().Msg(eventDebugWithDataMsg)
+	if !quieted("") {
+		rootLogger().Debug().Msg(eventDebugWithDataMsg)
+	}
 
-	tmp := shallowDupe(e.fields)
-	dupedFields := mergeFields(tmp, fields)
+	out := e.derive()
+	out.fields = mergeFieldsAt(out.fields, e.groups, fields)
+	return out
+}
 
-	return &event{
-		logger: e.logger,
-		fields: dupedFields,
+func (e *event) LogContextDone(ctx context.Context, msg string) (stop func()) {
+	return watchContextDone(ctx, e.derive(), msg)
+}
+
+func (e *event) TeeTo(w io.Writer) Emitter {
+	if w == nil {
+		return e.derive()
+	}
+	sinks := teeSinks(e.sinks, w)
+	lgr := e.logger.Output(zerolog.MultiLevelWriter(sinks...))
+
+	out := e.derive()
+	out.logger = &lgr
+	out.sinks = sinks
+	return out
+}
+
+func (e *event) WithGroupData(path []string, fields map[string]interface{}) Emitter {
+	out := e.derive()
+	out.fields = mergeFieldsAt(out.fields, appendGroups(e.groups, path...), fields)
+	return out
+}
+
+func (e *event) WithDataKey(key string) Emitter {
+	if key == "" {
+		key = dataFieldName
 	}
+	out := e.derive()
+	out.dataKey = key
+	return out
+}
+
+func (e *event) WithGroup(name string) Emitter {
+	out := e.derive()
+	out.groups = appendGroups(e.groups, name)
+	return out
+}
+
+func (e *event) Child(fields map[string]interface{}) Emitter {
+	out := e.derive()
+	out.fields = mergeFieldsAt(out.fields, e.groups, fields)
+	return out
+}
+
+func (e *event) WithRetry(attempt, max int, backoff time.Duration) Emitter {
+	out := e.derive()
+	out.fields = withRetryFields(e.fields, e.groups, attempt, max, backoff)
+	return out
+}
+
+func (e *event) Sample(rate float64) Emitter {
+	lgr := e.logger.Sample(rateSampler{rate: rate})
+
+	out := e.derive()
+	out.logger = &lgr
+	return out
+}
+
+func (e *event) WithError(err error) Emitter {
+	out := e.derive()
+	out.err = err
+	return out
+}
+
+// derive copies the event, with its own data fields, for the methods which
+// leave the receiver unchanged.
+func (e *event) derive() *event {
+	out := *e
+	out.fields = shallowDupe(e.fields)
+	return &out
 }
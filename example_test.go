@@ -48,6 +48,22 @@ func ExampleConfigure_possiblyUnintendedConfiguration() {
 	logg.Infof("anybody out there?")
 }
 
+func ExampleClose() {
+	// Write to a slow destination in the background.
+	var socket io.Writer
+	sink := logg.NewAsyncWriter(socket, 1024)
+
+	logg.Configure(
+		sink,
+		map[string]string{"branch_name": "main", "build_time": "20060102T150415", "commit_hash": "deadbeef"},
+	)
+
+	// Make sure everything is written before the application exits.
+	defer logg.Close()
+
+	logg.Infof("hello")
+}
+
 func ExampleCtxWithID() {
 	// Create a new context to ensure the same tracing ID on each subsequent
 	// tracing event.
@@ -0,0 +1,39 @@
+package logg
+
+import "io"
+
+// SetExit replaces the func called to exit the process. Call restore to put
+// back the original func.
+func SetExit(fn func(code int)) (restore func()) {
+	orig := exit
+	exit = fn
+	return func() { exit = orig }
+}
+
+// NewFromRoot initializes a logger from a new root logger, rather than the one
+// set up by Configure. This is useful for testing root logger configuration.
+func NewFromRoot(version map[string]string, w io.Writer) Emitter {
+	sub := newRoot(version, w)
+	return &logger{context: &sub, fields: make(map[string]interface{}), dataKey: dataFieldName, sinks: []io.Writer{w}}
+}
+
+// SetRootSink replaces the root logger with one that writes to w. Call restore
+// to put back the original root logger.
+func SetRootSink(w io.Writer) (restore func()) {
+	rootLogger() // make sure it's configured, so it isn't replaced later.
+	orig := root
+	root = newRoot(nil, w)
+	return func() { root = orig }
+}
+
+// SetSampleRand replaces the source of randomness for sampling events. Call
+// restore to put back the original func.
+func SetSampleRand(fn func() float64) (restore func()) {
+	orig := sampleRand
+	sampleRand = fn
+	return func() { sampleRand = orig }
+}
+
+// CloseSinks is closeSinks, exposed for testing without replacing the sinks
+// from Configure.
+var CloseSinks = closeSinks
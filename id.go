@@ -2,6 +2,8 @@ package logg
 
 import (
 	"context"
+	"strings"
+	"sync"
 
 	"github.com/rs/xid"
 	"github.com/rs/zerolog"
@@ -15,13 +17,113 @@ func CtxWithID(ctx context.Context) context.Context {
 	return out
 }
 
-// getSetID retrieves an existing unique id from ctx or creates one. In either
-// case, the output is a new context copied from the input.
+// traceIDFieldName is the logging entry key for a tracing ID.
+const traceIDFieldName = "x_trace_id"
+
+var (
+	idFallback    func(ctx context.Context) (string, bool)
+	idFallbackMu  sync.RWMutex
+	idGenerator   func() string
+	idGeneratorMu sync.RWMutex
+	normalizeIDs  bool
+	normalizeMu   sync.RWMutex
+)
+
+// SetIDFallback registers a func to look up a tracing ID when the context does
+// not have one set by this package. It's only consulted after the primary
+// lookup fails. This lets applications which store IDs elsewhere on a context,
+// such as under their own key, emit those IDs instead. The fallback should
+// output false when the context does not have an ID. Pass nil to remove it.
+func SetIDFallback(fn func(ctx context.Context) (string, bool)) {
+	idFallbackMu.Lock()
+	defer idFallbackMu.Unlock()
+	idFallback = fn
+}
+
+func lookupIDFallback(ctx context.Context) (id string, ok bool) {
+	idFallbackMu.RLock()
+	defer idFallbackMu.RUnlock()
+	if idFallback == nil {
+		return
+	}
+	return idFallback(ctx)
+}
+
+// SetIDGenerator registers a func to create new tracing IDs, such as ULIDs or
+// some other scheme, instead of the default xid. It's used by CtxWithID,
+// EnsureID and WithID when the context does not already have an ID. If the func
+// outputs an empty string, then the default is used. Pass nil to remove it.
+func SetIDGenerator(fn func() string) {
+	idGeneratorMu.Lock()
+	defer idGeneratorMu.Unlock()
+	idGenerator = fn
+}
+
+func generateID() (id string) {
+	idGeneratorMu.RLock()
+	defer idGeneratorMu.RUnlock()
+	if idGenerator == nil {
+		return
+	}
+	return idGenerator()
+}
+
+// SetIDNormalization turns on, or off, normalizing tracing IDs before they're
+// written. A normalized ID is lowercase, without any dashes, so that IDs from
+// mixed sources, such as SetIDFallback or SetIDGenerator, are easier to query.
+// The IDs on a context are not changed. It's off by default.
+func SetIDNormalization(on bool) {
+	normalizeMu.Lock()
+	defer normalizeMu.Unlock()
+	normalizeIDs = on
+}
+
+// outputID is the form of id written to the log, see SetIDNormalization.
+func outputID(id string) string {
+	normalizeMu.RLock()
+	defer normalizeMu.RUnlock()
+	if !normalizeIDs {
+		return id
+	}
+	return strings.ToLower(strings.ReplaceAll(id, "-", ""))
+}
+
+// EnsureID is like CtxWithID, but it also outputs the tracing ID. This could be
+// used at a request entry point, where the incoming context may or may not have
+// an ID yet.
+func EnsureID(ctx context.Context) (context.Context, string) {
+	return getSetID(ctx)
+}
+
+// idCtxKey is the context key for an ID from the func set by SetIDGenerator.
+// IDs from the default generator are stored by hlog instead.
+type idCtxKey struct{}
+
+// lookupID retrieves an existing unique id from ctx, without creating one.
+func lookupID(ctx context.Context) (id string, ok bool) {
+	if xID, found := hlog.IDFromCtx(ctx); found {
+		return xID.String(), true
+	}
+	if id, ok = ctx.Value(idCtxKey{}).(string); ok {
+		return
+	}
+	return lookupIDFallback(ctx)
+}
+
+// getSetID retrieves an existing unique id from ctx or creates one. When the id
+// already exists, the output context is the input context. Otherwise, it's a
+// new context with the id.
 func getSetID(ctx context.Context) (out context.Context, id string) {
-	xID, ok := hlog.IDFromCtx(ctx)
-	if !ok {
-		xID = xid.New()
+	var ok bool
+	if id, ok = lookupID(ctx); ok {
+		out = ctx
+		return
+	}
+	if id = generateID(); id != "" {
+		out = context.WithValue(ctx, idCtxKey{}, id)
+		return
 	}
+	xID := xid.New()
 	out = hlog.CtxWithID(ctx, xID)
 	id = xID.String()
 	return
@@ -30,6 +132,6 @@ func getSetID(ctx context.Context) (out context.Context, id string) {
 func newZerologCtxWithID(ctx context.Context, lgr *zerolog.Logger) *zerolog.Context {
 	next, id := getSetID(ctx)
 	next = lgr.WithContext(next)
-	ztx := zerolog.Ctx(next).With().Str("x_trace_id", id)
+	ztx := zerolog.Ctx(next).With().Str(traceIDFieldName, outputID(id))
 	return &ztx
 }
@@ -19,3 +19,74 @@ func TestID(t *testing.T) {
 		t.Errorf("wrong id, got %q, expected %q", got, exp)
 	}
 }
+
+func TestIDFallback(t *testing.T) {
+	type altKey struct{}
+
+	SetIDFallback(func(ctx context.Context) (string, bool) {
+		id, ok := ctx.Value(altKey{}).(string)
+		return id, ok
+	})
+	defer SetIDFallback(nil)
+
+	// Use the fallback when the context only has an ID at the alternate key.
+	ctx := context.WithValue(context.Background(), altKey{}, "alternate")
+	_, got := getSetID(ctx)
+	if got != "alternate" {
+		t.Errorf("wrong id, got %q, expected %q", got, "alternate")
+	}
+
+	// The primary lookup is still first.
+	ctx, exp := getSetID(context.Background())
+	_, got = getSetID(context.WithValue(ctx, altKey{}, "alternate"))
+	if got != exp {
+		t.Errorf("wrong id, got %q, expected %q", got, exp)
+	}
+
+	// Create a new ID when the fallback doesn't find one either.
+	_, got = getSetID(context.Background())
+	if got == "" || got == "alternate" {
+		t.Errorf("expected a new id, got %q", got)
+	}
+}
+
+func TestEnsureID(t *testing.T) {
+	t.Run("already present", func(t *testing.T) {
+		ctx, exp := getSetID(context.Background())
+		out, got := EnsureID(ctx)
+		if got != exp {
+			t.Errorf("wrong id, got %q, expected %q", got, exp)
+		}
+		if out != ctx {
+			t.Error("expected same context")
+		}
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		SetIDGenerator(func() string { return "generated" })
+		defer SetIDGenerator(nil)
+
+		ctx, got := EnsureID(context.Background())
+		if got != "generated" {
+			t.Errorf("wrong id, got %q, expected %q", got, "generated")
+		}
+
+		// The generated ID is found later on, rather than generating another.
+		if id, ok := lookupID(ctx); !ok || id != "generated" {
+			t.Errorf("wrong id, got %q, %t; expected %q, %t", id, ok, "generated", true)
+		}
+	})
+
+	t.Run("default generator", func(t *testing.T) {
+		SetIDGenerator(func() string { return "" })
+		defer SetIDGenerator(nil)
+
+		ctx, got := EnsureID(context.Background())
+		if got == "" {
+			t.Fatal("id should be non-empty")
+		}
+		if id, ok := lookupID(ctx); !ok || id != got {
+			t.Errorf("wrong id, got %q, %t; expected %q, %t", id, ok, got, true)
+		}
+	})
+}
@@ -6,18 +6,33 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 )
 
 var (
 	root          zerolog.Context
+	rootSinks     []io.Writer
 	configureOnce sync.Once
 	defaultSink   = os.Stderr
+	exit          = os.Exit
 )
 
-// dataFieldName is the logging entry key for any event-specific data.
-const dataFieldName = "data"
+const (
+	// dataFieldName is the logging entry key for any event-specific data.
+	dataFieldName = "data"
+	// environmentFieldName is the logging entry key for the deployment
+	// environment, read from the LOGG_ENV environment variable.
+	environmentFieldName = "environment"
+	// opIDFieldName is the logging entry key for an operation ID.
+	opIDFieldName = "op_id"
+	// retryFieldName is the data key for details about a retry attempt.
+	retryFieldName = "retry"
+	// batchErrorsFieldName is the logging entry key for the errors from
+	// Emitter.ErrorBatch.
+	batchErrorsFieldName = "errors"
+)
 
 // Configure initializes a root logger from which all subsequent logging events
 // are derived, provided there are no previous writes to the log.  If there are
@@ -31,19 +46,13 @@ const dataFieldName = "data"
 //
 // The version parameter may be empty, but it's recommended to put some metadata
 // here so you can associate an event with the source code version.
+//
+// If the environment variable LOGG_ENV is set, such as "production", then its
+// value is written to the environment field of every event.
 func Configure(w io.Writer, version map[string]string, moreSinks ...io.Writer) {
 	configureOnce.Do(func() {
-		sinks := append([]io.Writer{w}, moreSinks...)
-		m := zerolog.MultiLevelWriter(sinks...)
-		root = zerolog.New(m).With().Timestamp()
-
-		if version != nil {
-			dict := zerolog.Dict()
-			for key, val := range version {
-				dict = dict.Str(key, val)
-			}
-			root = root.Dict("version", dict)
-		}
+		rootSinks = append([]io.Writer{w}, moreSinks...)
+		root = newRoot(version, rootSinks...)
 
 		if strings.ToUpper(os.Getenv("LOGG_LEVEL")) == "DEBUG" {
 			lgr := root.Logger()
@@ -52,25 +61,139 @@ func Configure(w io.Writer, version map[string]string, moreSinks ...io.Writer) {
 	})
 }
 
+// newRoot creates the prototype for all logging events, which writes to sinks.
+func newRoot(version map[string]string, sinks ...io.Writer) zerolog.Context {
+	m := zerolog.MultiLevelWriter(sinks...)
+	out := zerolog.New(m).With().Timestamp()
+
+	if version != nil {
+		dict := zerolog.Dict()
+		for key, val := range version {
+			dict = dict.Str(key, val)
+		}
+		out = out.Dict("version", dict)
+	}
+
+	if env := os.Getenv("LOGG_ENV"); env != "" {
+		out = out.Str(environmentFieldName, env)
+	}
+
+	return out
+}
+
 // Errorf writes msg to the log at level error and additionally writes err to an
 // error field. If msg is a format string and there are args, then it works like
 // fmt.Printf.
 func Errorf(err error, msg string, args ...interface{}) {
+	if quieted("") {
+		return
+	}
 	rootLogger().Err(err).Msgf(msg, args...)
 }
 
 // Infof writes msg to the log at level info. If msg is a format string and
 // there are args, then it works like fmt.Printf.
 func Infof(msg string, args ...interface{}) {
+	if quieted("") {
+		return
+	}
 	rootLogger().Info().Msgf(msg, args...)
 }
 
-// An Emitter emitter writes to the log at info or error levels.
+// An Emitter emitter writes to the log at info or error levels. The methods
+// with a Context suffix also include any data fields on ctx, which are set by
+// WithDataContext. The Emitter's own data fields take precedence over those.
+//
+// The tracing ID for the Context methods is resolved in this order:
+//  1. the ID set on the Emitter with WithID.
+//  2. an existing ID on ctx, see CtxWithID and SetIDFallback.
+//  3. otherwise, there is no tracing ID.
+//
+// The methods which output an Emitter leave the receiver unchanged, except for
+// WithID, which modifies the receiver and outputs it.
 type Emitter interface {
+	// Infof writes msg to the log at level info. If msg is a format string
+	// and there are args, then it works like fmt.Printf.
 	Infof(msg string, args ...interface{})
+	// Errorf writes msg to the log at level error and writes err to the error
+	// field. If err is nil, then the preset error from WithError is used. If
+	// that's nil too, then the event is written at level info.
 	Errorf(err error, msg string, args ...interface{})
+	// InfofContext is like Infof, but it also writes the data fields, flags
+	// and tracing ID on ctx.
+	InfofContext(ctx context.Context, msg string, args ...interface{})
+	// ErrorfContext is like Errorf, but it also writes the data fields, flags
+	// and tracing ID on ctx.
+	ErrorfContext(ctx context.Context, err error, msg string, args ...interface{})
+	// WithID sets a tracing ID from ctx, creating one if ctx doesn't have one.
+	// Unlike the other methods here, it modifies the receiver.
 	WithID(ctx context.Context) Emitter
+	// WithData outputs an Emitter for one event, with fields merged into the
+	// data fields, within any groups. The input fields override conflicting
+	// keys.
 	WithData(fields map[string]interface{}) Emitter
+	// LogContextDone watches ctx in the background and writes msg at level
+	// info, with the context error in the error field, when ctx is done. It
+	// writes with the Emitter as it was at the time of the call. Call the stop
+	// func to stop watching once the operation finishes.
+	LogContextDone(ctx context.Context, msg string) (stop func())
+	// Msg writes the message template registered with code, see
+	// RegisterMessage, at level info. The code is written to the msg_code
+	// field. If nothing is registered with code, then the message is the code
+	// followed by any args.
+	Msg(code string, args ...interface{})
+	// Fatalf writes to the log at level error like Errorf, flushes any sinks
+	// that buffer writes, then exits the process with code 1. The event is
+	// written even in quiet mode.
+	Fatalf(err error, msg string, args ...interface{})
+	// TeeTo outputs an Emitter which writes to w in addition to the original
+	// sinks. A nil w is ignored.
+	TeeTo(w io.Writer) Emitter
+	// WithGroupData is like WithData, but the fields are nested under the keys
+	// in path, within any groups.
+	WithGroupData(path []string, fields map[string]interface{}) Emitter
+	// WithOpID outputs an Emitter which writes id to the op_id field. It
+	// identifies a local unit of work, unlike the tracing ID.
+	WithOpID(id string) Emitter
+	// WithDataKey outputs an Emitter which writes its data fields at key,
+	// rather than at "data". An empty key means the default.
+	WithDataKey(key string) Emitter
+	// WithGroup outputs an Emitter which nests the fields from subsequent calls
+	// to WithData, WithGroupData, Child and WithRetry under name. Existing
+	// fields are not nested. An empty name is ignored.
+	WithGroup(name string) Emitter
+	// Child is like WithData, but the output is meant to be long-lived, such
+	// as for a subsystem, rather than for one event.
+	Child(fields map[string]interface{}) Emitter
+	// WithRetry outputs an Emitter which writes attempt, max and backoff to
+	// the retry data field, within any groups. The backoff is in units of
+	// zerolog.DurationFieldUnit.
+	WithRetry(attempt, max int, backoff time.Duration) Emitter
+	// Err is like Errorf, using the error text as the message. If err is nil,
+	// then the preset error from WithError is used. If that's nil too, then
+	// the event is written at level info with a message saying so.
+	Err(err error)
+	// Sample outputs an Emitter which only writes about rate, a fraction from
+	// 0 to 1, of its info events. The decision is made separately for each
+	// event. Events at level error are always written. The rate replaces any
+	// rate from an earlier call to Sample, rather than compounding it.
+	Sample(rate float64) Emitter
+	// ErrorBatch writes one event at level error, with msg and an errors field
+	// listing each of errs, each with its own error and data fields. The
+	// preset error from WithError is not used.
+	ErrorBatch(msg string, errs []BatchError)
+	// WithError outputs an Emitter which writes err to the error field of
+	// subsequent events, including those at level info. An error passed to
+	// Errorf, ErrorfContext, Fatalf or Err takes precedence. A nil err means
+	// there's no preset error.
+	WithError(err error) Emitter
+}
+
+// A BatchError is one of many failures, with its own data fields, reported in
+// one event by Emitter.ErrorBatch.
+type BatchError struct {
+	Err    error
+	Fields map[string]interface{}
 }
 
 func rootLogger() *zerolog.Logger {
@@ -81,6 +204,105 @@ func rootLogger() *zerolog.Logger {
 	return &out
 }
 
+// watchContextDone starts a goroutine that writes msg to e at level info, with
+// ctx.Err() in the error field, once ctx is done. Level info stands in for a
+// warning, since a cancellation is often routine and shouldn't set off alerts
+// on errors. Calling stop makes the goroutine exit without logging if ctx is
+// not done yet. The stop func blocks until the goroutine has exited and it's
+// safe to call more than once. Pass a copy of the Emitter, so that the
+// goroutine doesn't race with the caller.
+func watchContextDone(ctx context.Context, e Emitter, msg string) (stop func()) {
+	quit, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+			e.WithError(ctx.Err()).Infof("%s", msg)
+		case <-quit:
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(quit) })
+		<-done
+	}
+	return
+}
+
+// A flusher is a sink that buffers writes, such as a *bufio.Writer.
+type flusher interface{ Flush() error }
+
+// A syncer is a sink that commits writes to stable storage, such as an
+// *os.File.
+type syncer interface{ Sync() error }
+
+// flushSinks flushes and syncs any sinks that support it. It attempts every
+// sink and returns the first error.
+func flushSinks(sinks []io.Writer) (err error) {
+	keep := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	for _, sink := range sinks {
+		if f, ok := sink.(flusher); ok {
+			keep(f.Flush())
+		}
+		if s, ok := sink.(syncer); ok {
+			keep(s.Sync())
+		}
+	}
+	return
+}
+
+// Close releases the sinks passed to Configure so that any buffered writes,
+// such as those in an AsyncWriter, are written. A sink with a Close method is
+// closed, otherwise a sink with a Flush method is flushed; other sinks are left
+// alone. The standard output and error streams are never closed. It's a good
+// idea to call this at the end of your application, i.e. defer logg.Close() in
+// the main func. Sinks passed to New are not affected.
+func Close() error { return closeSinks(rootSinks) }
+
+// closeSinks closes or flushes sinks. It attempts every sink and returns the
+// first error.
+func closeSinks(sinks []io.Writer) (err error) {
+	keep := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	for _, sink := range sinks {
+		if sink == os.Stdout || sink == os.Stderr {
+			continue
+		}
+		if c, ok := sink.(io.Closer); ok {
+			keep(c.Close())
+		} else if f, ok := sink.(flusher); ok {
+			keep(f.Flush())
+		}
+	}
+	return
+}
+
+// fatal writes the event, flushes sinks and then exits the process with a
+// non-zero code. Any flushing errors are ignored because there's nowhere left
+// to report them.
+func fatal(evt *zerolog.Event, sinks []io.Writer, msg string, args ...interface{}) {
+	evt.Msgf(msg, args...)
+	_ = flushSinks(sinks)
+	exit(1)
+}
+
+// teeSinks makes a new list of sinks from the existing ones and w, without
+// modifying the input sinks.
+func teeSinks(sinks []io.Writer, w io.Writer) []io.Writer {
+	out := make([]io.Writer, 0, len(sinks)+1)
+	return append(append(out, sinks...), w)
+}
+
 func shallowDupe(in map[string]interface{}) (out map[string]interface{}) {
 	out = make(map[string]interface{})
 	if in == nil {
@@ -102,10 +324,127 @@ func mergeFields(dst, src map[string]interface{}) map[string]interface{} {
 	return dst
 }
 
-func newZerologInfoEvent(lgr *zerolog.Logger, fields map[string]interface{}) *zerolog.Event {
-	return lgr.Info().Dict(dataFieldName, zerolog.Dict().Fields(fields))
+// mergeFieldsAt merges src into dst under the nested keys in path. Any maps
+// along the path are created as needed. Existing maps along the path are copied
+// before merging so that they aren't modified, but dst itself is modified.
+func mergeFieldsAt(dst map[string]interface{}, path []string, src map[string]interface{}) map[string]interface{} {
+	if len(path) == 0 {
+		return mergeFields(dst, src)
+	}
+
+	prev, _ := dst[path[0]].(map[string]interface{})
+	dst[path[0]] = mergeFieldsAt(shallowDupe(prev), path[1:], src)
+	return dst
+}
+
+// nilErrMsg is the message for an event from Emitter.Err with a nil error.
+const nilErrMsg = "logged a nil error"
+
+// errMsg is the message for an event from Emitter.Err.
+func errMsg(err error) string {
+	if err == nil {
+		return nilErrMsg
+	}
+	return err.Error()
+}
+
+// withRetryFields copies fields and adds details about a retry attempt, nested
+// under any groups.
+func withRetryFields(fields map[string]interface{}, groups []string, attempt, max int, backoff time.Duration) map[string]interface{} {
+	retry := map[string]interface{}{
+		"attempt": attempt,
+		"max":     max,
+		// Nested values are encoded by encoding/json, which would output
+		// nanoseconds. Match the unit of top-level duration fields instead.
+		"backoff": float64(backoff) / float64(zerolog.DurationFieldUnit),
+	}
+	return mergeFieldsAt(shallowDupe(fields), groups, map[string]interface{}{retryFieldName: retry})
+}
+
+// appendGroups makes a new group path from groups and names, without modifying
+// groups. Empty names are ignored.
+func appendGroups(groups []string, names ...string) []string {
+	out := make([]string, 0, len(groups)+len(names))
+	out = append(out, groups...)
+	for _, name := range names {
+		if name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// The newZerolog*Event functions output a nil event, which zerolog treats as a
+// no-op, when the event should be dropped in quiet mode. zerolog also outputs a
+// nil event when it's sampled out. Either way, the data fields are only encoded
+// for events which are written, so dropped events don't allocate. That goes
+// for merging the data fields on ctx too, in the Info and Error variants.
+
+func newZerologInfoEvent(ctx context.Context, lgr *zerolog.Logger, dataKey string, fields map[string]interface{}) *zerolog.Event {
+	if quieted("") {
+		return nil
+	}
+	evt := lgr.Info()
+	if evt == nil {
+		return nil
+	}
+	return evt.Dict(dataKey, zerolog.Dict().Fields(contextFields(ctx, fields)))
+}
+
+func newZerologErrorEvent(ctx context.Context, lgr *zerolog.Logger, err error, dataKey string, fields map[string]interface{}) *zerolog.Event {
+	if quieted("") {
+		return nil
+	}
+	evt := lgr.Err(err)
+	if evt == nil {
+		return nil
+	}
+	return evt.Dict(dataKey, zerolog.Dict().Fields(contextFields(ctx, fields)))
+}
+
+// newZerologFatalEvent is like newZerologErrorEvent, but it's never dropped in
+// quiet mode because it's the last chance to say why the process exits.
+func newZerologFatalEvent(lgr *zerolog.Logger, err error, dataKey string, fields map[string]interface{}) *zerolog.Event {
+	evt := lgr.Err(err)
+	if evt == nil {
+		return nil
+	}
+	return evt.Dict(dataKey, zerolog.Dict().Fields(fields))
+}
+
+func newZerologMsgEvent(lgr *zerolog.Logger, code, dataKey string, fields map[string]interface{}) *zerolog.Event {
+	if quieted(code) {
+		return nil
+	}
+	evt := lgr.Info()
+	if evt == nil {
+		return nil
+	}
+	return evt.Dict(dataKey, zerolog.Dict().Fields(fields)).Str(msgCodeFieldName, code)
+}
+
+func newZerologBatchEvent(lgr *zerolog.Logger, dataKey string, fields map[string]interface{}, errs []BatchError) *zerolog.Event {
+	if quieted("") {
+		return nil
+	}
+	evt := lgr.Error()
+	if evt == nil {
+		return nil
+	}
+
+	arr := zerolog.Arr()
+	for _, be := range errs {
+		arr = arr.Object(batchErrorObject{BatchError: be, dataKey: dataKey})
+	}
+	return evt.Dict(dataKey, zerolog.Dict().Fields(fields)).Array(batchErrorsFieldName, arr)
+}
+
+// batchErrorObject writes a BatchError in the same shape as an error event.
+type batchErrorObject struct {
+	BatchError
+	dataKey string
 }
 
-func newZerologErrorEvent(lgr *zerolog.Logger, err error, fields map[string]interface{}) *zerolog.Event {
-	return lgr.Err(err).Dict(dataFieldName, zerolog.Dict().Fields(fields))
+func (b batchErrorObject) MarshalZerologObject(e *zerolog.Event) {
+	e.AnErr(zerolog.ErrorFieldName, b.Err).Dict(b.dataKey, zerolog.Dict().Fields(b.Fields))
 }
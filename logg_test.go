@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -108,6 +110,79 @@ func TestLogg(t *testing.T) {
 	})
 }
 
+func TestErr(t *testing.T) {
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	logger.Err(errors.New("hello"))
+	testLogg(t, sink.Raw(), errors.New("hello"), "hello", false, map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	logger.WithData(map[string]interface{}{"bravo": true}).Err(errors.New("goodbye"))
+	testLogg(t, sink.Raw(), errors.New("goodbye"), "goodbye", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// nil error
+	logger.Err(nil)
+	testLogg(t, sink.Raw(), nil, "logged a nil error", false, map[string]interface{}{"sierra": "nevada"})
+	var parsedRoot map[string]interface{}
+	if err := json.Unmarshal(sink.Raw(), &parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+	if parsedRoot["level"] != "info" {
+		t.Errorf("wrong level; got %v, expected %q", parsedRoot["level"], "info")
+	}
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestErrorBatch(t *testing.T) {
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	logger.WithData(map[string]interface{}{"job": "import"}).ErrorBatch("batch failed", []logg.BatchError{
+		{Err: errors.New("alfa"), Fields: map[string]interface{}{"row": 1}},
+		{Err: errors.New("bravo"), Fields: map[string]interface{}{"row": 2, "column": "name"}},
+		{Err: errors.New("charlie")},
+	})
+
+	var parsedRoot struct {
+		Level   string                   `json:"level"`
+		Message string                   `json:"message"`
+		Data    map[string]interface{}   `json:"data"`
+		Errors  []map[string]interface{} `json:"errors"`
+	}
+	if err := json.Unmarshal(sink.Raw(), &parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+	if parsedRoot.Level != "error" {
+		t.Errorf("wrong level; got %q, expected %q", parsedRoot.Level, "error")
+	}
+	if parsedRoot.Message != "batch failed" {
+		t.Errorf("wrong message; got %q, expected %q", parsedRoot.Message, "batch failed")
+	}
+	expData := map[string]interface{}{"job": "import", "sierra": "nevada"}
+	if !reflect.DeepEqual(parsedRoot.Data, expData) {
+		t.Errorf("wrong data; got %v, expected %v", parsedRoot.Data, expData)
+	}
+	expErrors := []map[string]interface{}{
+		{"error": "alfa", "data": map[string]interface{}{"row": float64(1)}},
+		{"error": "bravo", "data": map[string]interface{}{"row": float64(2), "column": "name"}},
+		{"error": "charlie", "data": map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(parsedRoot.Errors, expErrors) {
+		t.Errorf("wrong errors; got %v, expected %v", parsedRoot.Errors, expErrors)
+	}
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
 func TestWithID(t *testing.T) {
 	t.Run("logger passes ID to event", func(t *testing.T) {
 		// The logger calls WithID, but the event does not.
@@ -152,6 +227,79 @@ func TestWithID(t *testing.T) {
 	})
 }
 
+func TestWithOpID(t *testing.T) {
+	const (
+		opIDKey    = "op_id"
+		traceIDKey = "x_trace_id"
+	)
+
+	testOpID := func(t *testing.T, in []byte, exp string) {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		if val, ok := parsedRoot[opIDKey]; !ok {
+			t.Errorf("expected to have key %q", opIDKey)
+		} else if val.(string) != exp {
+			t.Errorf("wrong value at %q; got %q, expected %q", opIDKey, val.(string), exp)
+		}
+		if t.Failed() {
+			t.Logf("%s", in)
+		}
+	}
+
+	t.Run("logger", func(t *testing.T) {
+		sink := newDataSink()
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink).WithID(context.Background()).WithOpID("op-alfa")
+
+		logger.Infof("logger with op id")
+		testLogg(t, sink.Raw(), nil, "logger with op id", true, map[string]interface{}{"sierra": "nevada"})
+		testOpID(t, sink.Raw(), "op-alfa")
+
+		logger.WithData(map[string]interface{}{"bravo": true}).Infof("event with op id")
+		testLogg(t, sink.Raw(), nil, "event with op id", true, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+		testOpID(t, sink.Raw(), "op-alfa")
+	})
+
+	t.Run("event", func(t *testing.T) {
+		sink := newDataSink()
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+		logger.WithData(map[string]interface{}{"bravo": true}).WithOpID("op-bravo").WithID(context.Background()).Infof("event with own op id")
+		testLogg(t, sink.Raw(), nil, "event with own op id", true, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+		testOpID(t, sink.Raw(), "op-bravo")
+	})
+
+	t.Run("receiver unchanged", func(t *testing.T) {
+		sink := newDataSink()
+		base := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+		base.WithOpID("op-alfa").Infof("a")
+		testOpID(t, sink.Raw(), "op-alfa")
+
+		base.WithOpID("op-bravo").Infof("b")
+		testOpID(t, sink.Raw(), "op-bravo")
+		if n := strings.Count(string(sink.Raw()), opIDKey); n != 1 {
+			t.Errorf("wrong count of %q values; got %d, expected %d", opIDKey, n, 1)
+		}
+
+		base.Infof("c")
+		if n := strings.Count(string(sink.Raw()), opIDKey); n != 0 {
+			t.Errorf("wrong count of %q values; got %d, expected %d", opIDKey, n, 0)
+		}
+
+		event := base.WithData(map[string]interface{}{"bravo": true})
+		event.WithOpID("op-charlie").Infof("d")
+		testOpID(t, sink.Raw(), "op-charlie")
+		event.Infof("e")
+		if n := strings.Count(string(sink.Raw()), opIDKey); n != 0 {
+			t.Errorf("wrong count of %q values; got %d, expected %d", opIDKey, n, 0)
+		}
+	})
+}
+
 func TestWithData(t *testing.T) {
 	t.Run("allows input fields to replace existing fields", func(t *testing.T) {
 		sink := newDataSink()
@@ -193,6 +341,610 @@ func TestWithData(t *testing.T) {
 	})
 }
 
+func TestEnvironment(t *testing.T) {
+	const environmentKey = "environment"
+
+	t.Run("set", func(t *testing.T) {
+		t.Setenv("LOGG_ENV", "production")
+		sink := newDataSink()
+		logg.NewFromRoot(nil, sink).Infof("with environment")
+		testLogg(t, sink.Raw(), nil, "with environment", false, map[string]interface{}{})
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(sink.Raw(), &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		if val, ok := parsedRoot[environmentKey]; !ok {
+			t.Errorf("expected to have key %q", environmentKey)
+		} else if val.(string) != "production" {
+			t.Errorf("wrong value at %q; got %q, expected %q", environmentKey, val.(string), "production")
+		}
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv("LOGG_ENV", "")
+		sink := newDataSink()
+		logg.NewFromRoot(nil, sink).Infof("without environment")
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(sink.Raw(), &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		if val, ok := parsedRoot[environmentKey]; ok {
+			t.Errorf("unexpected value at %q; got %v", environmentKey, val)
+		}
+	})
+}
+
+func TestDataKeyOrder(t *testing.T) {
+	// Map iteration order is random, but the output should be stable so that
+	// entries with the same data are easy to compare.
+	fields := map[string]interface{}{
+		"zulu": 1, "alfa": 2, "mike": 3, "charlie": 4, "yankee": 5,
+		"nested": map[string]interface{}{"zulu": 1, "alfa": 2, "mike": 3, "charlie": 4},
+	}
+	sink := newDataSink()
+	logger := logg.New(fields, sink)
+
+	var prev json.RawMessage
+	for i := 0; i < 10; i++ {
+		logger.Infof("test")
+
+		var parsedRoot map[string]json.RawMessage
+		if err := json.Unmarshal(sink.Raw(), &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		got := parsedRoot["data"]
+		if i > 0 && !bytes.Equal(got, prev) {
+			t.Errorf("data keys in different order\ngot      %s\nprevious %s", got, prev)
+		}
+		prev = got
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	// zerolog replaces invalid UTF-8 in strings with the replacement character,
+	// so the output is still valid JSON.
+	sink := newDataSink()
+	logg.New(map[string]interface{}{"raw": "a\xffb"}, sink).Infof("c\xfed")
+	if !json.Valid(sink.Raw()) {
+		t.Fatalf("invalid JSON %s", sink.Raw())
+	}
+	testLogg(t, sink.Raw(), nil, "c\ufffdd", false, map[string]interface{}{"raw": "a\ufffdb"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestLogContextDone(t *testing.T) {
+	t.Run("context canceled", func(t *testing.T) {
+		sink := make(chanSink, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		stop := logg.New(map[string]interface{}{"sierra": "nevada"}, sink).LogContextDone(ctx, "operation canceled")
+		defer stop()
+
+		cancel()
+		select {
+		case got := <-sink:
+			testLogg(t, got, context.Canceled, "operation canceled", false, map[string]interface{}{"sierra": "nevada"})
+			var parsedRoot map[string]interface{}
+			if err := json.Unmarshal(got, &parsedRoot); err != nil {
+				t.Fatal(err)
+			}
+			if parsedRoot["level"] != "info" {
+				t.Errorf("wrong level; got %v, expected %q", parsedRoot["level"], "info")
+			}
+			if t.Failed() {
+				t.Logf("%s", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	})
+
+	t.Run("receiver changed after", func(t *testing.T) {
+		sink := make(chanSink, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+		stop := logger.LogContextDone(ctx, "operation canceled")
+		defer stop()
+
+		// The event is from the logger as it was when LogContextDone was called.
+		logger.WithID(context.Background())
+		cancel()
+		select {
+		case got := <-sink:
+			testLogg(t, got, context.Canceled, "operation canceled", false, map[string]interface{}{"sierra": "nevada"})
+			if t.Failed() {
+				t.Logf("%s", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	})
+
+	t.Run("stopped before done", func(t *testing.T) {
+		sink := make(chanSink, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stop := logg.New(nil, sink).WithData(map[string]interface{}{"bravo": true}).LogContextDone(ctx, "operation canceled")
+
+		stop()
+		stop() // ok to call more than once
+		cancel()
+		select {
+		case got := <-sink:
+			t.Errorf("unexpected event %s", got)
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+}
+
+func TestFatalf(t *testing.T) {
+	var (
+		exitCode          int
+		flushedBeforeExit bool
+		sink              = &flushSink{DataSink: newDataSink()}
+	)
+	restore := logg.SetExit(func(code int) {
+		exitCode = code
+		flushedBeforeExit = sink.flushed
+	})
+	defer restore()
+
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	// test logger
+	logger.Fatalf(errors.New("hello"), "logger fatal")
+	testLogg(t, sink.Raw(), errors.New("hello"), "logger fatal", false, map[string]interface{}{"sierra": "nevada"})
+	if exitCode != 1 {
+		t.Errorf("wrong exit code; got %d, expected %d", exitCode, 1)
+	}
+	if !flushedBeforeExit {
+		t.Error("expected sink to be flushed before exit")
+	}
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// test event
+	exitCode, flushedBeforeExit, sink.flushed = 0, false, false
+	logger.WithData(map[string]interface{}{"bravo": true}).Fatalf(errors.New("goodbye"), "event fatal")
+	testLogg(t, sink.Raw(), errors.New("goodbye"), "event fatal", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+	if exitCode != 1 {
+		t.Errorf("wrong exit code; got %d, expected %d", exitCode, 1)
+	}
+	if !flushedBeforeExit {
+		t.Error("expected sink to be flushed before exit")
+	}
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestWithDataKey(t *testing.T) {
+	testDataKey := func(t *testing.T, in []byte, expKey string, expData map[string]interface{}) {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		if expKey != "data" {
+			if val, ok := parsedRoot["data"]; ok {
+				t.Errorf("unexpected value at %q; got %v", "data", val)
+			}
+		}
+		if val, ok := parsedRoot[expKey]; !ok {
+			t.Errorf("expected to have key %q", expKey)
+		} else if !reflect.DeepEqual(val, expData) {
+			t.Errorf("wrong value at %q; got %v, expected %v", expKey, val, expData)
+		}
+		if t.Failed() {
+			t.Logf("%s", in)
+		}
+	}
+
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	payloadLogger := logger.WithDataKey("payload")
+	payloadLogger.Infof("a")
+	testDataKey(t, sink.Raw(), "payload", map[string]interface{}{"sierra": "nevada"})
+
+	payloadLogger.WithData(map[string]interface{}{"bravo": true}).Infof("b")
+	testDataKey(t, sink.Raw(), "payload", map[string]interface{}{"bravo": true, "sierra": "nevada"})
+
+	logger.WithData(map[string]interface{}{"bravo": true}).WithDataKey("payload").Errorf(errors.New("oops"), "c")
+	testDataKey(t, sink.Raw(), "payload", map[string]interface{}{"bravo": true, "sierra": "nevada"})
+
+	// empty key means the default key.
+	payloadLogger.WithDataKey("").Infof("d")
+	testDataKey(t, sink.Raw(), "data", map[string]interface{}{"sierra": "nevada"})
+
+	// check that the original logger hasn't changed unexpectedly.
+	logger.Infof("e")
+	testDataKey(t, sink.Raw(), "data", map[string]interface{}{"sierra": "nevada"})
+}
+
+func TestWithGroupData(t *testing.T) {
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{
+		"sierra": "nevada",
+		"http":   map[string]interface{}{"method": "GET"},
+	}, sink)
+
+	event := logger.WithGroupData([]string{"http", "request"}, map[string]interface{}{"path": "/foo"})
+	event.Infof("a")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http": map[string]interface{}{
+			"method":  "GET",
+			"request": map[string]interface{}{"path": "/foo"},
+		},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// merge with an existing sibling group, from an event.
+	event.WithGroupData([]string{"http", "response"}, map[string]interface{}{"status": float64(200)}).Infof("b")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http": map[string]interface{}{
+			"method":   "GET",
+			"request":  map[string]interface{}{"path": "/foo"},
+			"response": map[string]interface{}{"status": float64(200)},
+		},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the first event's data hasn't changed unexpectedly.
+	event.Infof("c")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http": map[string]interface{}{
+			"method":  "GET",
+			"request": map[string]interface{}{"path": "/foo"},
+		},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the logger's data hasn't changed unexpectedly.
+	logger.Infof("d")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http":   map[string]interface{}{"method": "GET"},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestWithGroup(t *testing.T) {
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	// The logger's existing fields are not nested.
+	grouped := logger.WithGroup("http")
+	grouped.Infof("a")
+	testGroupData(t, sink.Raw(), map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	grouped.WithData(map[string]interface{}{"method": "GET"}).Infof("b")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http":   map[string]interface{}{"method": "GET"},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// An empty name is ignored.
+	grouped.WithGroup("").WithData(map[string]interface{}{"method": "PUT"}).Infof("c")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http":   map[string]interface{}{"method": "PUT"},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the original logger hasn't changed unexpectedly.
+	logger.WithData(map[string]interface{}{"method": "POST"}).Infof("d")
+	testGroupData(t, sink.Raw(), map[string]interface{}{"sierra": "nevada", "method": "POST"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// stacked groups.
+	grouped.WithGroup("request").WithData(map[string]interface{}{"path": "/foo"}).Infof("e")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http": map[string]interface{}{
+			"request": map[string]interface{}{"path": "/foo"},
+		},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// groups on an event.
+	logger.WithData(map[string]interface{}{"bravo": true}).WithGroup("http").WithData(map[string]interface{}{"method": "GET"}).Infof("f")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"bravo":  true,
+		"http":   map[string]interface{}{"method": "GET"},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		logger.WithRetry(attempt, 3, 250*time.Millisecond).Infof("retrying")
+		testGroupData(t, sink.Raw(), map[string]interface{}{
+			"sierra": "nevada",
+			"retry": map[string]interface{}{
+				"attempt": float64(attempt),
+				"max":     float64(3),
+				"backoff": float64(250), // corresponding input is a time.Duration.
+			},
+		})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	}
+
+	logger.WithData(map[string]interface{}{"bravo": true}).WithRetry(3, 3, time.Second).Errorf(errors.New("oops"), "gave up")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"bravo":  true,
+		"retry": map[string]interface{}{
+			"attempt": float64(3),
+			"max":     float64(3),
+			"backoff": float64(1000),
+		},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	logger.WithGroup("http").WithRetry(2, 3, time.Second).WithData(map[string]interface{}{"status": 503}).Infof("grouped")
+	testGroupData(t, sink.Raw(), map[string]interface{}{
+		"sierra": "nevada",
+		"http": map[string]interface{}{
+			"status": float64(503),
+			"retry": map[string]interface{}{
+				"attempt": float64(2),
+				"max":     float64(3),
+				"backoff": float64(1000),
+			},
+		},
+	})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the logger hasn't changed unexpectedly.
+	logger.Infof("done")
+	testGroupData(t, sink.Raw(), map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+// testGroupData compares the entire data field, which may have nested values,
+// to expData.
+func testGroupData(t *testing.T, in []byte, expData map[string]interface{}) {
+	t.Helper()
+
+	var parsedRoot struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(in, &parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsedRoot.Data, expData) {
+		t.Errorf("wrong data; got %v, expected %v", parsedRoot.Data, expData)
+	}
+}
+
+func TestWithError(t *testing.T) {
+	sink := newDataSink()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+	preset := errors.New("connection refused")
+	withErr := logger.WithError(preset)
+
+	withErr.Infof("retrying")
+	testLogg(t, sink.Raw(), preset, "retrying", false, map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	withErr.Errorf(nil, "gave up")
+	testLogg(t, sink.Raw(), preset, "gave up", false, map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// the error passed to Errorf takes precedence.
+	withErr.WithData(map[string]interface{}{"bravo": true}).Errorf(errors.New("timeout"), "gave up")
+	testLogg(t, sink.Raw(), errors.New("timeout"), "gave up", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// a nil preset error is the same as not calling WithError.
+	logger.WithError(nil).Infof("ok")
+	testLogg(t, sink.Raw(), nil, "ok", false, map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the logger hasn't changed unexpectedly.
+	logger.Infof("done")
+	testLogg(t, sink.Raw(), nil, "done", false, map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func TestAllocs(t *testing.T) {
+	// Dropped events should not allocate. This is a documented guarantee, see
+	// the README.
+	type testCase struct {
+		name string
+		emit func()
+	}
+
+	testAllocs := func(t *testing.T, tests []testCase) {
+		t.Helper()
+		for _, test := range tests {
+			if got := testing.AllocsPerRun(100, test.emit); got != 0 {
+				t.Errorf("%s; wrong number of allocations; got %v, expected %v", test.name, got, 0)
+			}
+		}
+	}
+
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, io.Discard)
+	event := logger.WithData(map[string]interface{}{"bravo": true})
+	ctx := logg.WithDataContext(context.Background(), map[string]interface{}{"zulu": true})
+
+	t.Run("quiet mode", func(t *testing.T) {
+		logg.SetQuietMode("TEST_ALLOCS")
+		defer logg.SetQuietMode()
+
+		testAllocs(t, []testCase{
+			{"package", func() { logg.Infof("test") }},
+			{"logger", func() { logger.Infof("test") }},
+			{"event", func() { event.Infof("test") }},
+			{"logger msg", func() { logger.Msg("TEST_DROPPED") }},
+			{"event error", func() { event.Errorf(nil, "test") }},
+			{"logger context", func() { logger.InfofContext(ctx, "test") }},
+			{"event context", func() { event.ErrorfContext(ctx, nil, "test") }},
+		})
+	})
+
+	t.Run("sampled out", func(t *testing.T) {
+		sampledLogger := logger.Sample(0)
+		sampledEvent := event.Sample(0)
+
+		testAllocs(t, []testCase{
+			{"logger", func() { sampledLogger.Infof("test") }},
+			{"event", func() { sampledEvent.Infof("test") }},
+			{"logger msg", func() { sampledLogger.Msg("TEST_DROPPED") }},
+			{"logger context", func() { sampledLogger.InfofContext(ctx, "test") }},
+			{"event context", func() { sampledEvent.InfofContext(ctx, "test") }},
+		})
+	})
+}
+
+func BenchmarkLogg(b *testing.B) {
+	fields := map[string]interface{}{
+		"bravo":   true,
+		"delta":   234 * time.Millisecond,
+		"foxtrot": float64(1.23),
+		"india":   10,
+		"sierra":  "nevada",
+	}
+
+	b.Run("Infof without fields", func(b *testing.B) {
+		logger := logg.New(nil, io.Discard)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Infof("test")
+		}
+	})
+
+	b.Run("Infof with 5 fields", func(b *testing.B) {
+		logger := logg.New(fields, io.Discard)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Infof("test")
+		}
+	})
+
+	b.Run("WithData then Infof", func(b *testing.B) {
+		logger := logg.New(nil, io.Discard)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.WithData(fields).Infof("test")
+		}
+	})
+
+	b.Run("WithID then Infof", func(b *testing.B) {
+		ctx := logg.CtxWithID(context.Background())
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logg.New(nil, io.Discard).WithID(ctx).Infof("test")
+		}
+	})
+
+	b.Run("FromContext then Infof", func(b *testing.B) {
+		ctx := logg.WithDataContext(context.Background(), fields)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logg.FromContext(ctx, io.Discard).Infof("test")
+		}
+	})
+
+	b.Run("Infof in quiet mode", func(b *testing.B) {
+		logg.SetQuietMode("BENCHMARK")
+		defer logg.SetQuietMode()
+
+		logger := logg.New(fields, io.Discard)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.Infof("test")
+		}
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("root", func(t *testing.T) {
+		// The root logger writes to standard error, which should be left open.
+		if err := logg.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stderr.Stat(); err != nil {
+			t.Errorf("expected standard error to be open; %v", err)
+		}
+	})
+
+	t.Run("sinks", func(t *testing.T) {
+		flushed := &flushSink{DataSink: newDataSink()}
+		closed := &closeSink{flushSink: flushSink{DataSink: newDataSink()}}
+		other := newDataSink()
+
+		if err := logg.CloseSinks([]io.Writer{flushed, closed, other, os.Stderr}); err != nil {
+			t.Fatal(err)
+		}
+		if !flushed.flushed {
+			t.Error("expected sink to be flushed")
+		}
+		if !closed.closed {
+			t.Error("expected sink to be closed")
+		}
+		if closed.flushed {
+			t.Error("expected closed sink to not also be flushed")
+		}
+	})
+}
+
 func testLogg(t *testing.T, in []byte, expErr error, expMessage string, expTraceID bool, expData map[string]interface{}) {
 	t.Helper()
 
@@ -295,3 +1047,35 @@ func (s *DataSink) Write(in []byte) (n int, e error) {
 
 // Raw outputs the buffer contents for inspection.
 func (s *DataSink) Raw() []byte { return s.buf.Bytes() }
+
+// chanSink sends a copy of each logging entry on the channel. It's useful for
+// events written from another goroutine.
+type chanSink chan []byte
+
+func (s chanSink) Write(in []byte) (n int, e error) {
+	s <- append([]byte(nil), in...)
+	n = len(in)
+	return
+}
+
+// flushSink is a DataSink that records whether or not it's been flushed.
+type flushSink struct {
+	*DataSink
+	flushed bool
+}
+
+func (s *flushSink) Flush() error {
+	s.flushed = true
+	return nil
+}
+
+// closeSink is a flushSink that records whether or not it's been closed.
+type closeSink struct {
+	flushSink
+	closed bool
+}
+
+func (s *closeSink) Close() error {
+	s.closed = true
+	return nil
+}
@@ -3,6 +3,7 @@ package logg
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -11,6 +12,11 @@ import (
 type logger struct {
 	context *zerolog.Context
 	fields  map[string]interface{}
+	dataKey string
+	sinks   []io.Writer
+	groups  []string
+	traced  bool
+	err     error
 }
 
 // New initializes a logger Emitter type and configures it so each event
@@ -21,42 +27,231 @@ func New(fields map[string]interface{}, sinks ...io.Writer) Emitter {
 	var sub zerolog.Context
 	if len(sinks) == 0 || sinks[0] == nil {
 		sub = rootLogger().With()
+		sinks = rootSinks
 	} else {
 		m := zerolog.MultiLevelWriter(sinks...)
 		sub = rootLogger().Output(m).With()
 	}
 
-	return &logger{context: &sub, fields: shallowDupe(fields)}
+	return &logger{context: &sub, fields: shallowDupe(fields), dataKey: dataFieldName, sinks: sinks}
+}
+
+// NewContext initializes a logger Emitter like New, with everything from ctx:
+// the tracing ID, if there is one, and any data fields from WithDataContext.
+// The input fields override conflicting keys. Unlike WithID, it doesn't create
+// an ID when ctx lacks one; the logger just has no tracing ID.
+func NewContext(ctx context.Context, fields map[string]interface{}, sinks ...io.Writer) Emitter {
+	out := New(ResolveFields(ctx, fields), sinks...).(*logger)
+	if id, ok := lookupID(ctx); ok {
+		ztx := out.context.Str(traceIDFieldName, outputID(id))
+		out.context = &ztx
+		out.traced = true
+	}
+	return out
 }
 
 func (l *logger) Errorf(err error, msg string, args ...interface{}) {
+	l.ErrorfContext(context.Background(), err, msg, args...)
+}
+
+func (l *logger) ErrorfContext(ctx context.Context, err error, msg string, args ...interface{}) {
+	if err == nil {
+		err = l.err
+	}
+	lgr := l.context.Logger()
+	evt := newZerologErrorEvent(ctx, &lgr, err, l.dataKey, l.fields)
+	addContext(ctx, evt, l.traced).Msgf(msg, args...)
+}
+
+// Err writes to the log at level error like Errorf, using the error text as
+// the message. If err is nil, then the preset error from WithError is used. If
+// that's nil too, then it's written at level info instead, the message says so
+// and there's no error field, rather than panicking.
+func (l *logger) Err(err error) {
+	if err == nil {
+		err = l.err
+	}
+	l.Errorf(err, "%s", errMsg(err))
+}
+
+// ErrorBatch writes one event to the log at level error, with an errors field
+// listing each of errs. Each item has its own error and data fields. This could
+// be used by a batch job to report many failures at once.
+func (l *logger) ErrorBatch(msg string, errs []BatchError) {
+	lgr := l.context.Logger()
+	newZerologBatchEvent(&lgr, l.dataKey, l.fields, errs).Msg(msg)
+}
+
+// Fatalf writes to the log at level error like Errorf, flushes any sinks that
+// buffer writes, then exits the process with code 1.
+func (l *logger) Fatalf(err error, msg string, args ...interface{}) {
+	if err == nil {
+		err = l.err
+	}
 	lgr := l.context.Logger()
-	newZerologErrorEvent(&lgr, err, l.fields).Msgf(msg, args...)
+	fatal(newZerologFatalEvent(&lgr, err, l.dataKey, l.fields), l.sinks, msg, args...)
 }
 
 func (l *logger) Infof(msg string, args ...interface{}) {
+	l.InfofContext(context.Background(), msg, args...)
+}
+
+func (l *logger) InfofContext(ctx context.Context, msg string, args ...interface{}) {
 	lgr := l.context.Logger()
-	newZerologInfoEvent(&lgr, l.fields).Msgf(msg, args...)
+	evt := newZerologInfoEvent(ctx, &lgr, l.dataKey, l.fields).AnErr(zerolog.ErrorFieldName, l.err)
+	addContext(ctx, evt, l.traced).Msgf(msg, args...)
+}
+
+// Msg writes the message template registered with code to the log at level
+// info. The code is written to the msg_code field. If nothing is registered
+// with code, then the message is the code followed by any args.
+func (l *logger) Msg(code string, args ...interface{}) {
+	lgr := l.context.Logger()
+	evt := newZerologMsgEvent(&lgr, code, l.dataKey, l.fields).AnErr(zerolog.ErrorFieldName, l.err)
+	evt.Msgf(lookupMessage(code, len(args)), args...)
 }
 
 func (l *logger) WithID(ctx context.Context) Emitter {
 	lgr := l.context.Logger()
 	l.context = newZerologCtxWithID(ctx, &lgr)
+	l.traced = true
 	return l
 }
 
+// WithOpID creates a logger with an operation ID. Unlike the tracing ID, which
+// may span a whole distributed request, the operation ID identifies some local
+// unit of work. The receiver is unchanged, so a long-lived logger can start
+// each operation with its own ID.
+func (l *logger) WithOpID(id string) Emitter {
+	return l.derive(l.context.Logger().With().Str(opIDFieldName, id).Logger())
+}
+
 // WithData prepares a logging entry and captures any event-specific data in
-// fields. Call the Emitter methods to write to the log.
+// fields. If the logger has groups, see WithGroup, then the fields are nested
+// under the groups. Call the Emitter methods to write to the log.
 func (l *logger) WithData(fields map[string]interface{}) Emitter {
-	logger := l.context.Logger()
-
 	// use original l.fields as a base, but let the input fields override any
 	// conflict keys for the output event.
 	tmp := shallowDupe(l.fields)
-	dupedFields := mergeFields(tmp, fields)
+	dupedFields := mergeFieldsAt(tmp, l.groups, fields)
+
+	return l.newEvent(dupedFields)
+}
+
+// LogContextDone watches ctx in the background and writes an info level event
+// with msg, and the context error, when ctx is done. This can help diagnose
+// premature cancellations in long operations. Call the stop func to stop
+// watching once the operation finishes. Later changes to the receiver, such as
+// WithID, don't affect the event.
+func (l *logger) LogContextDone(ctx context.Context, msg string) (stop func()) {
+	return watchContextDone(ctx, l.derive(l.context.Logger()), msg)
+}
+
+// TeeTo creates a logger that writes to w in addition to the original sinks.
+// This could be used to capture a subsystem's events in a dedicated file. A nil
+// w is ignored. The receiver is unchanged.
+func (l *logger) TeeTo(w io.Writer) Emitter {
+	if w == nil {
+		return l.derive(l.context.Logger())
+	}
+	sinks := teeSinks(l.sinks, w)
+	out := l.derive(l.context.Logger().Output(zerolog.MultiLevelWriter(sinks...)))
+	out.sinks = sinks
+	return out
+}
+
+// WithGroupData is like WithData, but the fields are nested under the keys in
+// path, within the data field and any groups. Fields are merged with any
+// existing maps along the path, preferring the input fields on conflicting
+// keys.
+func (l *logger) WithGroupData(path []string, fields map[string]interface{}) Emitter {
+	tmp := shallowDupe(l.fields)
+	dupedFields := mergeFieldsAt(tmp, appendGroups(l.groups, path...), fields)
+
+	return l.newEvent(dupedFields)
+}
+
+// WithDataKey creates a logger which emits its data fields at key, rather than
+// the default data key. An empty key means the default. The receiver is
+// unchanged.
+func (l *logger) WithDataKey(key string) Emitter {
+	if key == "" {
+		key = dataFieldName
+	}
+	out := l.derive(l.context.Logger())
+	out.dataKey = key
+	return out
+}
+
+// WithGroup creates a logger which nests the fields from subsequent calls to
+// WithData, WithGroupData, Child and WithRetry under name. The logger's existing
+// fields are not nested. An empty name is ignored. The receiver is unchanged.
+func (l *logger) WithGroup(name string) Emitter {
+	out := l.derive(l.context.Logger())
+	out.groups = appendGroups(l.groups, name)
+	return out
+}
+
+// Child creates a logger which inherits the receiver's sinks, tracing ID and
+// data fields, then merges in fields. The input fields override conflicting
+// keys. Unlike WithData, the output is another logger rather than an event. The
+// receiver is unchanged.
+func (l *logger) Child(fields map[string]interface{}) Emitter {
+	out := l.derive(l.context.Logger())
+	out.fields = mergeFieldsAt(out.fields, l.groups, fields)
+	return out
+}
+
+// WithRetry creates a logger which adds details about a retry attempt to the
+// data fields of subsequent events, under the retry key within any groups. This
+// helps retry loops produce consistent events. The receiver is unchanged.
+func (l *logger) WithRetry(attempt, max int, backoff time.Duration) Emitter {
+	out := l.derive(l.context.Logger())
+	out.fields = withRetryFields(l.fields, l.groups, attempt, max, backoff)
+	return out
+}
+
+// Sample creates a logger which only writes about rate, a fraction from 0 to
+// 1, of its info events. It could be used for chatty events that aren't needed
+// every time. The decision is made separately for each event. Error events are
+// always written. The receiver is unchanged.
+func (l *logger) Sample(rate float64) Emitter {
+	return l.derive(l.context.Logger().Sample(rateSampler{rate: rate}))
+}
+
+// WithError creates a logger which writes err to the error field of subsequent
+// events, including those at level info. This could be used when many events
+// are about the same underlying error, such as in a retry loop. An error passed
+// to the Errorf method takes precedence; a nil error means there's no preset.
+// The receiver is unchanged.
+func (l *logger) WithError(err error) Emitter {
+	out := l.derive(l.context.Logger())
+	out.err = err
+	return out
+}
+
+// derive copies the logger for the methods which leave the receiver unchanged.
+// The copy has its own zerolog context, based on lgr, and its own data fields.
+func (l *logger) derive(lgr zerolog.Logger) *logger {
+	sub := lgr.With()
+	out := *l
+	out.context = &sub
+	out.fields = shallowDupe(l.fields)
+	return &out
+}
+
+// newEvent prepares a logging entry with fields. Everything else is inherited
+// from the logger.
+func (l *logger) newEvent(fields map[string]interface{}) *event {
+	lgr := l.context.Logger()
 
 	return &event{
-		logger: &logger,
-		fields: dupedFields,
+		logger:  &lgr,
+		fields:  fields,
+		dataKey: l.dataKey,
+		sinks:   l.sinks,
+		groups:  l.groups,
+		traced:  l.traced,
+		err:     l.err,
 	}
 }
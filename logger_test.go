@@ -1,6 +1,8 @@
 package logg_test
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/rafaelespinoza/logg"
@@ -54,3 +56,154 @@ func TestLogger(t *testing.T) {
 		}
 	})
 }
+
+func TestNewContext(t *testing.T) {
+	parseTraceID := func(t *testing.T, in []byte) string {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		id, _ := parsedRoot["x_trace_id"].(string)
+		return id
+	}
+
+	t.Run("with id", func(t *testing.T) {
+		ctx := logg.CtxWithID(context.Background())
+		sink := newDataSink()
+
+		// Establish the expected ID from the context.
+		logg.New(nil, sink).WithID(ctx).Infof("a")
+		exp := parseTraceID(t, sink.Raw())
+
+		logger := logg.NewContext(ctx, map[string]interface{}{"sierra": "nevada"}, sink)
+		logger.Infof("b")
+		testLogg(t, sink.Raw(), nil, "b", true, map[string]interface{}{"sierra": "nevada"})
+		if got := parseTraceID(t, sink.Raw()); got != exp {
+			t.Errorf("wrong id; got %q, expected %q", got, exp)
+		}
+
+		// The ID is not duplicated by the Context methods.
+		logger.InfofContext(ctx, "c")
+		testLogg(t, sink.Raw(), nil, "c", true, map[string]interface{}{"sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("with data", func(t *testing.T) {
+		ctx := logg.CtxWithID(logg.WithDataContext(context.Background(), map[string]interface{}{"sierra": "madre", "zulu": true}))
+		sink := newDataSink()
+
+		// The input fields take precedence.
+		logg.NewContext(ctx, map[string]interface{}{"sierra": "nevada"}, sink).Infof("a")
+		testLogg(t, sink.Raw(), nil, "a", true, map[string]interface{}{"sierra": "nevada", "zulu": true})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("without id", func(t *testing.T) {
+		sink := newDataSink()
+		logg.NewContext(context.Background(), map[string]interface{}{"sierra": "nevada"}, sink).Infof("a")
+		testLogg(t, sink.Raw(), nil, "a", false, map[string]interface{}{"sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+}
+
+func TestTeeTo(t *testing.T) {
+	t.Run("logger", func(t *testing.T) {
+		orig, tee := newDataSink(), newDataSink()
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, orig)
+
+		logger.TeeTo(tee).Infof("both")
+		testLogg(t, orig.Raw(), nil, "both", false, map[string]interface{}{"sierra": "nevada"})
+		testLogg(t, tee.Raw(), nil, "both", false, map[string]interface{}{"sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", orig.Raw())
+			t.Logf("%s", tee.Raw())
+		}
+
+		// check that the original logger doesn't write to the tee'd sink.
+		tee.buf.Reset()
+		logger.Infof("original only")
+		testLogg(t, orig.Raw(), nil, "original only", false, map[string]interface{}{"sierra": "nevada"})
+		if len(tee.Raw()) > 0 {
+			t.Errorf("unexpected write to tee'd sink; %s", tee.Raw())
+		}
+	})
+
+	t.Run("event", func(t *testing.T) {
+		orig, tee := newDataSink(), newDataSink()
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, orig)
+
+		logger.WithData(map[string]interface{}{"bravo": true}).TeeTo(tee).Infof("both")
+		testLogg(t, orig.Raw(), nil, "both", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+		testLogg(t, tee.Raw(), nil, "both", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", orig.Raw())
+			t.Logf("%s", tee.Raw())
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		orig := newDataSink()
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, orig)
+
+		logger.TeeTo(nil).Infof("logger")
+		testLogg(t, orig.Raw(), nil, "logger", false, map[string]interface{}{"sierra": "nevada"})
+
+		logger.WithData(map[string]interface{}{"bravo": true}).TeeTo(nil).Infof("event")
+		testLogg(t, orig.Raw(), nil, "event", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+		if t.Failed() {
+			t.Logf("%s", orig.Raw())
+		}
+	})
+}
+
+func TestChild(t *testing.T) {
+	const traceIDKey = "x_trace_id"
+
+	parseTraceID := func(t *testing.T, in []byte) string {
+		t.Helper()
+
+		var parsedRoot map[string]interface{}
+		if err := json.Unmarshal(in, &parsedRoot); err != nil {
+			t.Fatal(err)
+		}
+		id, _ := parsedRoot[traceIDKey].(string)
+		return id
+	}
+
+	sink := newDataSink()
+	parent := logg.New(map[string]interface{}{"foo": "alfa", "sierra": "nevada"}, sink).WithID(context.Background())
+	parent.Infof("parent")
+	parentID := parseTraceID(t, sink.Raw())
+
+	child := parent.Child(map[string]interface{}{"foo": "bravo", "zulu": true})
+	child.Infof("child")
+	testLogg(t, sink.Raw(), nil, "child", true, map[string]interface{}{"foo": "bravo", "sierra": "nevada", "zulu": true})
+	if got := parseTraceID(t, sink.Raw()); got != parentID {
+		t.Errorf("wrong id; got %q, expected %q", got, parentID)
+	}
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// a child's event inherits everything too.
+	child.WithData(map[string]interface{}{"bravo": true}).Infof("child event")
+	testLogg(t, sink.Raw(), nil, "child event", true, map[string]interface{}{"bravo": true, "foo": "bravo", "sierra": "nevada", "zulu": true})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	// check that the parent hasn't changed unexpectedly.
+	parent.Infof("parent again")
+	testLogg(t, sink.Raw(), nil, "parent again", true, map[string]interface{}{"foo": "alfa", "sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
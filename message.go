@@ -0,0 +1,76 @@
+package logg
+
+import (
+	"strings"
+	"sync"
+)
+
+// msgCodeFieldName is the logging entry key for a registered message code.
+const msgCodeFieldName = "msg_code"
+
+var (
+	messages   = make(map[string]string)
+	messagesMu sync.RWMutex
+
+	// quietCodes are the only message codes allowed through in quiet mode.
+	// When it's nil, quiet mode is off.
+	quietCodes map[string]struct{}
+	quietMu    sync.RWMutex
+)
+
+// RegisterMessage associates a message template with a code. The template may
+// be a format string, which works like fmt.Printf when the message is emitted
+// with Emitter.Msg. Registering the same code again replaces its template.
+//
+// Stable codes are handy for alerting and for managing messages in one place.
+func RegisterMessage(code, template string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	messages[code] = template
+}
+
+// lookupMessage gets the template registered with code. If nothing is
+// registered, then the code itself is the template, with any percent signs
+// escaped, followed by a verb for each of the numArgs args so that they're
+// still in the message.
+func lookupMessage(code string, numArgs int) string {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+	if template, ok := messages[code]; ok {
+		return template
+	}
+	return strings.ReplaceAll(code, "%", "%%") + strings.Repeat(" %v", numArgs)
+}
+
+// SetQuietMode silences every event, at any level, except for those emitted by
+// Emitter.Msg with one of the input codes. This can be a targeted way to reduce
+// noise during incident response. Events from Emitter.Fatalf are always written,
+// so the reason for exiting isn't lost. Call it without any codes to turn quiet
+// mode off.
+func SetQuietMode(codes ...string) {
+	quietMu.Lock()
+	defer quietMu.Unlock()
+
+	if len(codes) == 0 {
+		quietCodes = nil
+		return
+	}
+
+	quietCodes = make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		quietCodes[code] = struct{}{}
+	}
+}
+
+// quieted reports whether or not an event with the message code should be
+// dropped. Use an empty code for events without one.
+func quieted(code string) bool {
+	quietMu.RLock()
+	defer quietMu.RUnlock()
+
+	if quietCodes == nil {
+		return false
+	}
+	_, ok := quietCodes[code]
+	return !ok
+}
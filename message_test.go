@@ -0,0 +1,137 @@
+package logg_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rafaelespinoza/logg"
+)
+
+func TestMsg(t *testing.T) {
+	const msgCodeKey = "msg_code"
+
+	logg.RegisterMessage("TEST_0001", "hello %s, you are %d")
+
+	t.Run("registered", func(t *testing.T) {
+		sink := newDataSink()
+		logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+		logger.Msg("TEST_0001", "alfa", 1)
+		testLogg(t, sink.Raw(), nil, "hello alfa, you are 1", false, map[string]interface{}{"sierra": "nevada"})
+		testMsgCode(t, sink.Raw(), msgCodeKey, "TEST_0001")
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+
+		logger.WithData(map[string]interface{}{"bravo": true}).Msg("TEST_0001", "bravo", 2)
+		testLogg(t, sink.Raw(), nil, "hello bravo, you are 2", false, map[string]interface{}{"bravo": true, "sierra": "nevada"})
+		testMsgCode(t, sink.Raw(), msgCodeKey, "TEST_0001")
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("unregistered", func(t *testing.T) {
+		sink := newDataSink()
+		logg.New(nil, sink).Msg("TEST_UNKNOWN")
+		testLogg(t, sink.Raw(), nil, "TEST_UNKNOWN", false, map[string]interface{}{})
+		testMsgCode(t, sink.Raw(), msgCodeKey, "TEST_UNKNOWN")
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+
+	t.Run("unregistered with args", func(t *testing.T) {
+		sink := newDataSink()
+		logg.New(nil, sink).Msg("TEST_UNKNOWN_100%", 1, "alfa")
+		testLogg(t, sink.Raw(), nil, "TEST_UNKNOWN_100% 1 alfa", false, map[string]interface{}{})
+		testMsgCode(t, sink.Raw(), msgCodeKey, "TEST_UNKNOWN_100%")
+		if t.Failed() {
+			t.Logf("%s", sink.Raw())
+		}
+	})
+}
+
+func TestSetQuietMode(t *testing.T) {
+	logg.RegisterMessage("TEST_QUIET_0001", "allowed")
+	logg.RegisterMessage("TEST_QUIET_0002", "silenced")
+
+	logg.SetQuietMode("TEST_QUIET_0001")
+	defer logg.SetQuietMode()
+
+	sink, rootSink := newDataSink(), newDataSink()
+	restore := logg.SetRootSink(rootSink)
+	defer restore()
+	logger := logg.New(map[string]interface{}{"sierra": "nevada"}, sink)
+
+	logger.Msg("TEST_QUIET_0001")
+	testLogg(t, sink.Raw(), nil, "allowed", false, map[string]interface{}{"sierra": "nevada"})
+	testMsgCode(t, sink.Raw(), "msg_code", "TEST_QUIET_0001")
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+
+	silenced := []func(){
+		func() { logger.Msg("TEST_QUIET_0002") },
+		func() { logger.Infof("info") },
+		func() { logger.Errorf(errors.New("oops"), "error") },
+		func() { logger.WithData(map[string]interface{}{"bravo": true}).Infof("event info") },
+		func() {
+			logger.WithData(map[string]interface{}{"bravo": true}).Errorf(errors.New("oops"), "event error")
+		},
+		func() {
+			logger.WithData(map[string]interface{}{"bravo": true}).WithData(map[string]interface{}{"zulu": true}).Infof("event data")
+		},
+	}
+	for i, emit := range silenced {
+		sink.buf.Reset()
+		rootSink.buf.Reset()
+		emit()
+		if len(sink.Raw()) > 0 {
+			t.Errorf("item[%d]; expected event to be dropped; got %s", i, sink.Raw())
+		}
+		if len(rootSink.Raw()) > 0 {
+			t.Errorf("item[%d]; expected nothing written to the root logger; got %s", i, rootSink.Raw())
+		}
+	}
+
+	// fatal events are still written.
+	var exitCode int
+	restoreExit := logg.SetExit(func(code int) { exitCode = code })
+	defer restoreExit()
+	for i, emitter := range []logg.Emitter{logger, logger.WithData(map[string]interface{}{"bravo": true})} {
+		sink.buf.Reset()
+		exitCode = 0
+		emitter.Fatalf(errors.New("oops"), "fatal")
+		if len(sink.Raw()) == 0 {
+			t.Errorf("item[%d]; expected fatal event to be written", i)
+		}
+		if exitCode != 1 {
+			t.Errorf("item[%d]; wrong exit code; got %d, expected %d", i, exitCode, 1)
+		}
+	}
+
+	// turning off quiet mode lets events through again.
+	logg.SetQuietMode()
+	logger.Infof("loud")
+	testLogg(t, sink.Raw(), nil, "loud", false, map[string]interface{}{"sierra": "nevada"})
+	if t.Failed() {
+		t.Logf("%s", sink.Raw())
+	}
+}
+
+func testMsgCode(t *testing.T, in []byte, key, exp string) {
+	t.Helper()
+
+	var parsedRoot map[string]interface{}
+	if err := json.Unmarshal(in, &parsedRoot); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, ok := parsedRoot[key]; !ok {
+		t.Errorf("expected to have key %q", key)
+	} else if val.(string) != exp {
+		t.Errorf("wrong value at %q; got %q, expected %q", key, val.(string), exp)
+	}
+}
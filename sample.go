@@ -0,0 +1,28 @@
+package logg
+
+import (
+	"math/rand"
+
+	"github.com/rs/zerolog"
+)
+
+// sampleRand outputs a pseudo-random number in [0.0, 1.0) to decide whether or
+// not to keep a sampled event. It's a variable so tests can be deterministic.
+var sampleRand = rand.Float64 // #nosec G404 -- sampling doesn't need a secure source.
+
+// rateSampler is a zerolog.Sampler, which keeps about rate of the info events.
+// Events above level info, such as errors, are always kept.
+type rateSampler struct{ rate float64 }
+
+func (s rateSampler) Sample(lvl zerolog.Level) bool {
+	switch {
+	case lvl > zerolog.InfoLevel:
+		return true
+	case s.rate <= 0:
+		return false
+	case s.rate >= 1:
+		return true
+	default:
+		return sampleRand() < s.rate
+	}
+}
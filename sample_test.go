@@ -0,0 +1,95 @@
+package logg_test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/rafaelespinoza/logg"
+)
+
+func TestSample(t *testing.T) {
+	const numEvents = 100
+
+	countWrites := func(emitter logg.Emitter, sink *countSink) int {
+		sink.n = 0
+		for i := 0; i < numEvents; i++ {
+			emitter.Infof("test")
+		}
+		return sink.n
+	}
+
+	t.Run("none", func(t *testing.T) {
+		sink := &countSink{}
+		logger := logg.New(nil, sink)
+		if got := countWrites(logger.Sample(0), sink); got != 0 {
+			t.Errorf("wrong number of writes; got %d, expected %d", got, 0)
+		}
+		if got := countWrites(logger.WithData(map[string]interface{}{"bravo": true}).Sample(0), sink); got != 0 {
+			t.Errorf("wrong number of writes; got %d, expected %d", got, 0)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		sink := &countSink{}
+		logger := logg.New(nil, sink)
+
+		logger.Sample(0).Errorf(errors.New("oops"), "test")
+		logger.Sample(0).Err(errors.New("oops"))
+		logger.WithData(map[string]interface{}{"bravo": true}).Sample(0).Errorf(errors.New("oops"), "test")
+		logger.Sample(0).ErrorBatch("test", []logg.BatchError{{Err: errors.New("oops")}})
+		if sink.n != 4 {
+			t.Errorf("wrong number of writes; got %d, expected %d", sink.n, 4)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		sink := &countSink{}
+		logger := logg.New(nil, sink)
+		if got := countWrites(logger.Sample(1), sink); got != numEvents {
+			t.Errorf("wrong number of writes; got %d, expected %d", got, numEvents)
+		}
+		if got := countWrites(logger.WithData(map[string]interface{}{"bravo": true}).Sample(1), sink); got != numEvents {
+			t.Errorf("wrong number of writes; got %d, expected %d", got, numEvents)
+		}
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		const rate = 0.3
+
+		// Calculate the expected count with an identically-seeded source.
+		var exp int
+		expRand := rand.New(rand.NewSource(1)) // #nosec G404 -- a fixed seed makes the test deterministic.
+		for i := 0; i < numEvents; i++ {
+			if expRand.Float64() < rate {
+				exp++
+			}
+		}
+
+		restore := logg.SetSampleRand(rand.New(rand.NewSource(1)).Float64) // #nosec G404 -- same as above.
+		defer restore()
+
+		sink := &countSink{}
+		logger := logg.New(nil, sink)
+		got := countWrites(logger.Sample(rate), sink)
+		if got != exp {
+			t.Errorf("wrong number of writes; got %d, expected %d", got, exp)
+		}
+		if got == 0 || got == numEvents {
+			t.Errorf("expected some, but not all, events to be written; got %d", got)
+		}
+
+		// check that the original logger hasn't changed unexpectedly.
+		if got := countWrites(logger, sink); got != numEvents {
+			t.Errorf("wrong number of writes; got %d, expected %d", got, numEvents)
+		}
+	})
+}
+
+// countSink counts the number of writes.
+type countSink struct{ n int }
+
+func (s *countSink) Write(in []byte) (int, error) {
+	s.n++
+	return len(in), nil
+}